@@ -0,0 +1,183 @@
+package wgclient
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// allowListRule is one CIDR-scoped allow/deny rule.
+type allowListRule struct {
+	Net   *net.IPNet
+	Allow bool
+}
+
+// AllowList is a CIDR-keyed allow/deny list for peer AllowedIPs, resolved
+// by longest-prefix-match: the most specific matching rule wins, and an
+// IP matching nothing is allowed by default. This mirrors nebula's
+// AllowList/RemoteAllowList, minus the nested bitwise trie — rule sets
+// here are small enough that a sorted scan is plenty fast.
+//
+// AllowList also carries per-user overrides, consulted before falling
+// back to the global rules.
+type AllowList struct {
+	rules  []allowListRule
+	byUser map[string][]allowListRule
+}
+
+// NewAllowListFromConfig builds an AllowList from the AllowList/DenyList
+// and UserAllowList/UserDenyList fields of c, the same JSON config file
+// that holds Users.
+func NewAllowListFromConfig(c Config) (*AllowList, error) {
+	rules, err := buildAllowListRules(c.AllowList, c.DenyList)
+	if err != nil {
+		return nil, fmt.Errorf("allow_list/deny_list: %w", err)
+	}
+
+	byUser := make(map[string][]allowListRule)
+	for user, allow := range c.UserAllowList {
+		r, err := buildAllowListRules(allow, c.UserDenyList[user])
+		if err != nil {
+			return nil, fmt.Errorf("user_allow_list/user_deny_list for %q: %w", user, err)
+		}
+		byUser[user] = r
+	}
+	for user, deny := range c.UserDenyList {
+		if _, ok := byUser[user]; ok {
+			continue
+		}
+		r, err := buildAllowListRules(nil, deny)
+		if err != nil {
+			return nil, fmt.Errorf("user_deny_list for %q: %w", user, err)
+		}
+		byUser[user] = r
+	}
+
+	return &AllowList{rules: rules, byUser: byUser}, nil
+}
+
+func buildAllowListRules(allow, deny []string) ([]allowListRule, error) {
+	var rules []allowListRule
+	for _, c := range allow {
+		n, err := parseAllowListCIDR(c)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, allowListRule{Net: n, Allow: true})
+	}
+	for _, c := range deny {
+		n, err := parseAllowListCIDR(c)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, allowListRule{Net: n, Allow: false})
+	}
+	sort.SliceStable(rules, func(i, j int) bool {
+		return maskLen(rules[i].Net) > maskLen(rules[j].Net)
+	})
+	return rules, nil
+}
+
+// parseAllowListCIDR accepts both "10.0.0.0/8" CIDR notation and a bare
+// "10.0.0.5" host, which is treated as a /32 (or /128 for IPv6).
+func parseAllowListCIDR(s string) (*net.IPNet, error) {
+	if _, n, err := net.ParseCIDR(s); err == nil {
+		return n, nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid CIDR or IP %q", s)
+	}
+	bits := 32
+	if ip.To4() == nil {
+		bits = 128
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+func maskLen(n *net.IPNet) int {
+	ones, _ := n.Mask.Size()
+	return ones
+}
+
+// Allowed reports whether ip passes the global allow/deny rules.
+func (a *AllowList) Allowed(ip net.IP) bool {
+	return evalAllowListRules(a.rules, ip)
+}
+
+// AllowedForUser reports whether ip passes user's override rules, falling
+// back to the global rules if user has none or none of them match.
+func (a *AllowList) AllowedForUser(user string, ip net.IP) bool {
+	if rules, ok := a.byUser[user]; ok {
+		for _, r := range rules {
+			if r.Net.Contains(ip) {
+				return r.Allow
+			}
+		}
+	}
+	return a.Allowed(ip)
+}
+
+// evalAllowListRules returns the Allow value of the first (most specific,
+// since rules is sorted longest-prefix-first) matching rule, defaulting
+// to true when nothing matches.
+func evalAllowListRules(rules []allowListRule, ip net.IP) bool {
+	for _, r := range rules {
+		if r.Net.Contains(ip) {
+			return r.Allow
+		}
+	}
+	return true
+}
+
+// FilterAllowedIPs drops entries (each a "ip/mask" string, as produced by
+// allowedIPEntry) that the global allow/deny rules reject.
+func (a *AllowList) FilterAllowedIPs(entries []string) []string {
+	out := entries[:0:0]
+	for _, e := range entries {
+		ip, _, err := net.ParseCIDR(e)
+		if err != nil {
+			out = append(out, e)
+			continue
+		}
+		if a.Allowed(ip) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// PeersForUser is PeersForClientConfig filtered through al for user, the
+// key used in Config.Users and in the allow/deny list's per-user
+// overrides. A peer is dropped only if every one of its AllowedIPs
+// entries is denied for this user.
+func (c Config) PeersForUser(user string, cc ClientConfig, al *AllowList) ([]Peer, error) {
+	peers, err := c.PeersForClientConfig(cc)
+	if err != nil {
+		return nil, err
+	}
+	if al == nil {
+		return peers, nil
+	}
+	out := peers[:0:0]
+	for _, p := range peers {
+		if al.peerAllowedForUser(user, p) {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (a *AllowList) peerAllowedForUser(user string, p Peer) bool {
+	for _, cidr := range strings.Split(p.AllowedIPs, ", ") {
+		ip, _, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			continue
+		}
+		if a.AllowedForUser(user, ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package wgclient
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowList(t *testing.T) {
+	conf := Config{
+		AllowList: []string{"10.0.0.0/8"},
+		DenyList:  []string{"10.1.0.0/16"},
+		UserAllowList: map[string][]string{
+			"1": {"10.1.0.0/16"},
+		},
+	}
+	al, err := NewAllowListFromConfig(conf)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	t.Run("global allow", func(t *testing.T) {
+		assert.True(t, al.Allowed(net.ParseIP("10.2.3.4")))
+	})
+
+	t.Run("global deny, more specific wins", func(t *testing.T) {
+		assert.False(t, al.Allowed(net.ParseIP("10.1.2.3")))
+	})
+
+	t.Run("unmatched defaults to allow", func(t *testing.T) {
+		assert.True(t, al.Allowed(net.ParseIP("8.8.8.8")))
+	})
+
+	t.Run("per-user override beats global deny", func(t *testing.T) {
+		assert.True(t, al.AllowedForUser("1", net.ParseIP("10.1.2.3")))
+	})
+
+	t.Run("user without override falls back to global", func(t *testing.T) {
+		assert.False(t, al.AllowedForUser("2", net.ParseIP("10.1.2.3")))
+	})
+}
+
+func TestAllowListFilterAllowedIPs(t *testing.T) {
+	al, err := NewAllowListFromConfig(Config{
+		DenyList: []string{"10.1.0.0/16"},
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	filtered := al.FilterAllowedIPs([]string{"10.1.0.5/32", "10.2.0.5/32", "not-a-cidr"})
+	assert.Equal(t, []string{"10.2.0.5/32", "not-a-cidr"}, filtered)
+}
+
+func TestPeersForUser(t *testing.T) {
+	conf := Config{
+		Interfaces: Interfaces{
+			0: {Endpoint: "primary:51820", PublicKey: "primary-pub", AllowedIPs: []string{"10.1.0.0/24"}},
+			1: {Endpoint: "fallback:51820", PublicKey: "fallback-pub", AllowedIPs: []string{"10.2.0.0/24"}},
+		},
+		DenyList: []string{"10.1.0.0/16"},
+	}
+	al, err := NewAllowListFromConfig(conf)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	peers, err := conf.PeersForUser("1", ClientConfig{IF: 0, CombineIFs: []int{1}}, al)
+	if !assert.NoError(t, err) {
+		return
+	}
+	if !assert.Len(t, peers, 1) {
+		return
+	}
+	assert.Equal(t, "fallback-pub", peers[0].PublicKey)
+}
+
+func TestNewAllowListFromConfigInvalidCIDR(t *testing.T) {
+	_, err := NewAllowListFromConfig(Config{AllowList: []string{"not-a-cidr"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,121 @@
+package wgclient
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/tracklib/wgclient/qr"
+)
+
+// RenderClientConfigQR renders renderContext's client config and encodes
+// it as a scannable QR PNG, the way the official mobile apps expect to
+// import a config.
+func RenderClientConfigQR(renderContext TemplateContext) ([]byte, error) {
+	conf, err := RenderClientConfig(renderContext)
+	if err != nil {
+		return nil, err
+	}
+	code, err := qr.Encode(conf)
+	if err != nil {
+		return nil, err
+	}
+	return code.PNG()
+}
+
+// RenderClientConfigQRANSI is RenderClientConfigQR, rendered as ANSI
+// half-block art for scanning straight out of a terminal.
+func RenderClientConfigQRANSI(renderContext TemplateContext) (string, error) {
+	conf, err := RenderClientConfig(renderContext)
+	if err != nil {
+		return "", err
+	}
+	code, err := qr.Encode(conf)
+	if err != nil {
+		return "", err
+	}
+	return code.ANSI(), nil
+}
+
+// ClientConfigManifest accompanies a bundled client config, recording
+// who it was generated for and a hash to verify the .conf in the bundle
+// hasn't been altered since. It is not a cryptographic signature: this
+// package has no signing key infrastructure, so ConfigSHA256 is an
+// integrity check, not an authenticity one.
+type ClientConfigManifest struct {
+	User         string    `json:"user"`
+	Interface    int       `json:"if"`
+	GeneratedAt  time.Time `json:"generated_at"`
+	ConfigSHA256 string    `json:"config_sha256"`
+}
+
+// RenderClientConfigBundle packages a rendered client config, its QR
+// code, and a ClientConfigManifest into a single zip archive: one
+// artifact to hand to user for cc.
+func RenderClientConfigBundle(renderContext TemplateContext, user User, cc ClientConfig) ([]byte, error) {
+	conf, err := RenderClientConfig(renderContext)
+	if err != nil {
+		return nil, err
+	}
+	code, err := qr.Encode(conf)
+	if err != nil {
+		return nil, err
+	}
+	qrPNG, err := code.PNG()
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(conf)
+	manifest := ClientConfigManifest{
+		User:         user.Name,
+		Interface:    cc.IF,
+		GeneratedAt:  time.Now().UTC(),
+		ConfigSHA256: hex.EncodeToString(sum[:]),
+	}
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	confName := user.Filename(cc)
+	for _, f := range []struct {
+		name string
+		data []byte
+	}{
+		{confName, conf},
+		{user.QRFilename(cc), qrPNG},
+		{"manifest.json", manifestJSON},
+	} {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(f.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("closing bundle zip: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// QRFilename is Filename with the image extension, for the QR code
+// sibling to a rendered client config.
+func (w User) QRFilename(cc ClientConfig) string {
+	return strings.TrimSuffix(w.Filename(cc), ".conf") + ".png"
+}
+
+// BundleFilename is Filename with the .zip extension, for the config +
+// QR + manifest bundle produced by RenderClientConfigBundle.
+func (w User) BundleFilename(cc ClientConfig) string {
+	return strings.TrimSuffix(w.Filename(cc), ".conf") + ".zip"
+}
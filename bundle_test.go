@@ -0,0 +1,84 @@
+package wgclient
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testTemplateContext() TemplateContext {
+	return TemplateContext{
+		PrivateKey: "priv-key",
+		Addresses:  []Address{{IP: "99.99.99.100", Prefix: 32}},
+		DNS:        "1,2",
+		Peers: []Peer{
+			{
+				PublicKey:           "peer-pub-key",
+				Endpoint:            "peer-endpoint",
+				AllowedIPs:          "1.1.1.1/32",
+				PersistentKeepalive: 25,
+			},
+		},
+	}
+}
+
+func TestRenderClientConfigQR(t *testing.T) {
+	png, err := RenderClientConfigQR(testTemplateContext())
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "\x89PNG\r\n\x1a\n", string(png[:8]))
+}
+
+func TestRenderClientConfigQRANSI(t *testing.T) {
+	ansi, err := RenderClientConfigQRANSI(testTemplateContext())
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NotEmpty(t, ansi)
+}
+
+func TestRenderClientConfigBundle(t *testing.T) {
+	user := User{Name: "alice"}
+	cc := ClientConfig{IF: 0}
+
+	data, err := RenderClientConfigBundle(testTemplateContext(), user, cc)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if !assert.NoError(t, err) {
+		return
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	assert.ElementsMatch(t, []string{user.Filename(cc), user.QRFilename(cc), "manifest.json"}, names)
+
+	for _, f := range zr.File {
+		if f.Name != "manifest.json" {
+			continue
+		}
+		rc, err := f.Open()
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer rc.Close()
+		var manifest ClientConfigManifest
+		assert.NoError(t, json.NewDecoder(rc).Decode(&manifest))
+		assert.Equal(t, "alice", manifest.User)
+		assert.Equal(t, 0, manifest.Interface)
+		assert.NotEmpty(t, manifest.ConfigSHA256)
+	}
+}
+
+func TestUserQRAndBundleFilename(t *testing.T) {
+	user := User{Name: "name"}
+	assert.Equal(t, "name_0.png", user.QRFilename(ClientConfig{}))
+	assert.Equal(t, "name_0.zip", user.BundleFilename(ClientConfig{}))
+}
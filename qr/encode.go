@@ -0,0 +1,105 @@
+package qr
+
+import "fmt"
+
+// bitBuffer is an MSB-first bit sequence, built up during data encoding.
+type bitBuffer struct {
+	bits []bool
+}
+
+func (b *bitBuffer) appendBits(val uint32, n int) {
+	for i := n - 1; i >= 0; i-- {
+		b.bits = append(b.bits, (val>>uint(i))&1 == 1)
+	}
+}
+
+func (b *bitBuffer) len() int { return len(b.bits) }
+
+func (b *bitBuffer) bytes() []byte {
+	out := make([]byte, (len(b.bits)+7)/8)
+	for i, bit := range b.bits {
+		if bit {
+			out[i/8] |= 1 << uint(7-i%8)
+		}
+	}
+	return out
+}
+
+// chooseVersion returns the smallest supported version whose byte-mode
+// capacity fits dataLen bytes.
+func chooseVersion(dataLen int) (version, error) {
+	for _, v := range versions {
+		// mode(4) + count indicator + dataLen*8 must fit; the terminator
+		// is shortened or dropped when no room is left; see encodeData.
+		headerBits := 4 + v.charCountBits()
+		capBits := v.dataCodewords() * 8
+		if headerBits+dataLen*8 <= capBits {
+			return v, nil
+		}
+	}
+	return version{}, fmt.Errorf("qr: %d bytes exceeds the largest supported QR version's capacity", dataLen)
+}
+
+// encodeData builds the data codeword sequence (byte mode), padded to
+// v's full data capacity with the terminator, bit-alignment padding, and
+// the standard 0xEC/0x11 pad bytes.
+func encodeData(data []byte, v version) []byte {
+	var bb bitBuffer
+	bb.appendBits(0b0100, 4) // byte mode
+	bb.appendBits(uint32(len(data)), v.charCountBits())
+	for _, by := range data {
+		bb.appendBits(uint32(by), 8)
+	}
+
+	capBits := v.dataCodewords() * 8
+	if term := capBits - bb.len(); term > 0 {
+		if term > 4 {
+			term = 4
+		}
+		bb.appendBits(0, term)
+	}
+	for bb.len()%8 != 0 {
+		bb.appendBits(0, 1)
+	}
+
+	pad := [2]byte{0xEC, 0x11}
+	for i := 0; bb.len()/8 < v.dataCodewords(); i++ {
+		bb.appendBits(uint32(pad[i%2]), 8)
+	}
+	return bb.bytes()
+}
+
+// interleave splits dataCodewords into blocks sized per v.blockLens (which
+// may mix two lengths one codeword apart, for version 10+), computes each
+// block's Reed-Solomon error-correction codewords, and interleaves data
+// then EC codewords column-wise as required by the QR spec: short blocks
+// simply run out and are skipped in the final data column(s).
+func interleave(dataCodewords []byte, v version) []byte {
+	blocks := make([][]byte, len(v.blockLens))
+	ecBlocks := make([][]byte, len(v.blockLens))
+	maxLen := 0
+	offset := 0
+	for i, l := range v.blockLens {
+		blocks[i] = dataCodewords[offset : offset+l]
+		offset += l
+		ecBlocks[i] = rsEncode(blocks[i], v.ecPerBlock)
+		if l > maxLen {
+			maxLen = l
+		}
+	}
+
+	out := make([]byte, 0, v.totalCodewords)
+	for i := 0; i < maxLen; i++ {
+		for _, block := range blocks {
+			if i < len(block) {
+				out = append(out, block[i])
+			}
+		}
+	}
+	for i := 0; i < v.ecPerBlock; i++ {
+		for _, block := range ecBlocks {
+			out = append(out, block[i])
+		}
+	}
+	return out
+}
@@ -0,0 +1,75 @@
+package qr
+
+// GF(256) arithmetic over the primitive polynomial x^8+x^4+x^3+x^2+1
+// (0x11D), as used by QR code Reed-Solomon error correction.
+
+var gfExp [512]byte
+var gfLog [256]byte
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		gfExp[i] = byte(x)
+		gfLog[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11D
+		}
+	}
+	for i := 255; i < 512; i++ {
+		gfExp[i] = gfExp[i-255]
+	}
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+// polyMul multiplies two polynomials over GF(256), each given as
+// coefficients highest-degree first.
+func polyMul(a, b []byte) []byte {
+	res := make([]byte, len(a)+len(b)-1)
+	for i, ac := range a {
+		if ac == 0 {
+			continue
+		}
+		for j, bc := range b {
+			if bc == 0 {
+				continue
+			}
+			res[i+j] ^= gfMul(ac, bc)
+		}
+	}
+	return res
+}
+
+// rsGeneratorPoly returns the degree-n Reed-Solomon generator polynomial,
+// coefficients highest-degree first.
+func rsGeneratorPoly(degree int) []byte {
+	g := []byte{1}
+	for i := 0; i < degree; i++ {
+		g = polyMul(g, []byte{1, gfExp[i]})
+	}
+	return g
+}
+
+// rsEncode returns the ecLen error-correction codewords for data, computed
+// as the remainder of data(x)*x^ecLen divided by the generator polynomial.
+func rsEncode(data []byte, ecLen int) []byte {
+	gen := rsGeneratorPoly(ecLen)
+	rem := make([]byte, len(data)+ecLen)
+	copy(rem, data)
+	for i := 0; i < len(data); i++ {
+		coef := rem[i]
+		if coef == 0 {
+			continue
+		}
+		for j, gc := range gen {
+			rem[i+j] ^= gfMul(gc, coef)
+		}
+	}
+	return rem[len(data):]
+}
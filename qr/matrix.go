@@ -0,0 +1,250 @@
+package qr
+
+// matrix is the module grid being built for one QR symbol. reserved marks
+// function-pattern and format/version-info modules so data placement and
+// masking skip them.
+type matrix struct {
+	size     int
+	modules  [][]bool
+	reserved [][]bool
+}
+
+func newMatrix(v version) *matrix {
+	size := v.size()
+	m := &matrix{size: size, modules: make([][]bool, size), reserved: make([][]bool, size)}
+	for i := range m.modules {
+		m.modules[i] = make([]bool, size)
+		m.reserved[i] = make([]bool, size)
+	}
+	m.placeFinderPatterns()
+	m.placeTimingPatterns()
+	m.placeAlignmentPatterns(v)
+	m.placeDarkModule(v)
+	m.reserveFormatInfo()
+	if bits, ok := versionInfoBits[v.number]; ok {
+		m.placeVersionInfo(bits)
+	}
+	return m
+}
+
+func (m *matrix) set(r, c int, dark bool) {
+	m.modules[r][c] = dark
+	m.reserved[r][c] = true
+}
+
+// finderAnchors returns the top-left corner of each of the three 7x7
+// finder patterns.
+func (m *matrix) finderAnchors() [][2]int {
+	return [][2]int{{0, 0}, {0, m.size - 7}, {m.size - 7, 0}}
+}
+
+func (m *matrix) placeFinderPatterns() {
+	for _, pos := range m.finderAnchors() {
+		m.placeFinderPattern(pos[0], pos[1])
+	}
+}
+
+// overlapsFinderPattern reports whether (r, c) falls within any finder
+// pattern's 7x7 box plus its 1-module separator - the same footprint
+// placeFinderPattern draws. Used to exclude alignment pattern centers
+// that would collide with a finder pattern; unlike the shared reserved
+// flag, this doesn't also match the timing pattern track, which
+// legitimately gets overwritten by an alignment pattern where the two
+// coincide (e.g. version 7's (6,22)).
+func (m *matrix) overlapsFinderPattern(r, c int) bool {
+	for _, pos := range m.finderAnchors() {
+		if r >= pos[0]-1 && r <= pos[0]+7 && c >= pos[1]-1 && c <= pos[1]+7 {
+			return true
+		}
+	}
+	return false
+}
+
+// placeFinderPattern draws one 7x7 finder pattern plus its 1-module
+// separator border, anchored at the top-left corner (r, c).
+func (m *matrix) placeFinderPattern(r, c int) {
+	for dr := -1; dr <= 7; dr++ {
+		for dc := -1; dc <= 7; dc++ {
+			rr, cc := r+dr, c+dc
+			if rr < 0 || rr >= m.size || cc < 0 || cc >= m.size {
+				continue
+			}
+			dark := false
+			switch {
+			case dr == -1 || dr == 7 || dc == -1 || dc == 7:
+				dark = false // separator
+			case dr == 0 || dr == 6 || dc == 0 || dc == 6:
+				dark = true // outer ring
+			case dr >= 2 && dr <= 4 && dc >= 2 && dc <= 4:
+				dark = true // center 3x3
+			default:
+				dark = false
+			}
+			m.set(rr, cc, dark)
+		}
+	}
+}
+
+func (m *matrix) placeTimingPatterns() {
+	for i := 8; i < m.size-8; i++ {
+		dark := i%2 == 0
+		m.set(6, i, dark)
+		m.set(i, 6, dark)
+	}
+}
+
+func (m *matrix) placeAlignmentPatterns(v version) {
+	coords := v.alignmentCoords
+	for _, r := range coords {
+		for _, c := range coords {
+			if m.overlapsFinderPattern(r, c) {
+				continue
+			}
+			m.placeAlignmentPattern(r, c)
+		}
+	}
+}
+
+// placeAlignmentPattern draws one 5x5 alignment pattern centered at (r, c).
+func (m *matrix) placeAlignmentPattern(r, c int) {
+	for dr := -2; dr <= 2; dr++ {
+		for dc := -2; dc <= 2; dc++ {
+			dark := dr == -2 || dr == 2 || dc == -2 || dc == 2 || (dr == 0 && dc == 0)
+			m.set(r+dr, c+dc, dark)
+		}
+	}
+}
+
+func (m *matrix) placeDarkModule(v version) {
+	m.set(4*v.number+9, 8, true)
+}
+
+// reserveFormatInfo marks the two 15-bit format-info strips as reserved;
+// their actual bits are written later once the mask is chosen.
+func (m *matrix) reserveFormatInfo() {
+	for i := 0; i <= 8; i++ {
+		if i != 6 {
+			m.reserved[8][i] = true
+			m.reserved[i][8] = true
+		}
+	}
+	for i := 0; i < 8; i++ {
+		m.reserved[8][m.size-1-i] = true
+		m.reserved[m.size-1-i][8] = true
+	}
+}
+
+func (m *matrix) placeVersionInfo(bits uint32) {
+	// Two copies of the 18-bit version info block: a 6x3 block above the
+	// bottom-left finder pattern, and its 3x6 transpose left of the
+	// top-right finder pattern.
+	for i := 0; i < 18; i++ {
+		bit := (bits>>uint(i))&1 == 1
+		r := i % 3
+		c := i / 3
+		m.set(m.size-11+r, c, bit)
+		m.set(c, m.size-11+r, bit)
+	}
+}
+
+// placeData writes codewords into the matrix in the standard bottom-up/
+// top-down zig-zag over column pairs, skipping the vertical timing
+// column and any reserved (function-pattern/format-info) modules.
+func (m *matrix) placeData(codewords []byte) {
+	totalBits := len(codewords) * 8
+	bitIdx := 0
+	upward := true
+	for col := m.size - 1; col > 0; col -= 2 {
+		if col == 6 {
+			col--
+		}
+		for i := 0; i < m.size; i++ {
+			row := i
+			if upward {
+				row = m.size - 1 - i
+			}
+			for _, c := range [2]int{col, col - 1} {
+				if m.reserved[row][c] {
+					continue
+				}
+				bit := false
+				if bitIdx < totalBits {
+					by := codewords[bitIdx/8]
+					bit = (by>>uint(7-bitIdx%8))&1 == 1
+				}
+				m.modules[row][c] = bit
+				bitIdx++
+			}
+		}
+		upward = !upward
+	}
+}
+
+// applyMask0 XORs mask pattern 0 ((row+col)%2==0) into every non-reserved
+// module. QR allows any of 8 mask patterns; a fixed mask is still a
+// perfectly valid, scannable symbol as long as the format info correctly
+// records which one was used (format info below always records mask 0),
+// it just forgoes the extra scan-reliability optimization bigger encoders
+// perform by picking the lowest-penalty mask.
+func (m *matrix) applyMask0() {
+	for r := 0; r < m.size; r++ {
+		for c := 0; c < m.size; c++ {
+			if m.reserved[r][c] {
+				continue
+			}
+			if (r+c)%2 == 0 {
+				m.modules[r][c] = !m.modules[r][c]
+			}
+		}
+	}
+}
+
+// placeFormatInfo writes the BCH(15,5)-encoded format info (EC level L,
+// mask 0) into its two reserved strips, two copies for redundancy.
+func (m *matrix) placeFormatInfo() {
+	bits := formatInfoBits()
+	get := func(i int) bool { return (bits>>uint(i))&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		m.modules[8][i] = get(i)
+	}
+	m.modules[8][7] = get(6)
+	m.modules[8][8] = get(7)
+	m.modules[7][8] = get(8)
+	for i := 9; i < 15; i++ {
+		m.modules[14-i][8] = get(i)
+	}
+
+	for i := 0; i < 8; i++ {
+		m.modules[m.size-1-i][8] = get(i)
+	}
+	for i := 8; i < 15; i++ {
+		m.modules[8][m.size-15+i] = get(i)
+	}
+	m.modules[m.size-8][8] = true // always dark
+}
+
+// formatInfoBits computes the 15-bit BCH-encoded format info for error
+// correction level L (bits 01) and mask pattern 0, XORed with the fixed
+// mask 0x5412 per the QR spec.
+func formatInfoBits() uint32 {
+	const ecLevelL = 0b01
+	const mask = 0
+	data := uint32(ecLevelL<<3 | mask)
+	const genPoly = 0x537
+	rem := data << 10
+	for i := 14; i >= 10; i-- {
+		if rem&(1<<uint(i)) != 0 {
+			rem ^= genPoly << uint(i-10)
+		}
+	}
+	return ((data << 10) | rem) ^ 0x5412
+}
+
+// build runs the full pipeline from raw codewords to a finished, masked
+// matrix with format info written in.
+func (m *matrix) build(codewords []byte) {
+	m.placeData(codewords)
+	m.applyMask0()
+	m.placeFormatInfo()
+}
@@ -0,0 +1,35 @@
+package qr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAlignmentPatternOverwritesTimingTrack covers version 7, whose
+// alignment coordinates {6, 22, 38} put a pattern at (6, 22) and (22, 6) -
+// squarely on the row-6/col-6 timing track. Those centers don't overlap
+// any finder pattern, so the alignment ring must still be drawn there,
+// overwriting the timing modules underneath.
+func TestAlignmentPatternOverwritesTimingTrack(t *testing.T) {
+	var v7 version
+	for _, v := range versions {
+		if v.number == 7 {
+			v7 = v
+		}
+	}
+	if !assert.Equal(t, 7, v7.number) {
+		return
+	}
+
+	m := newMatrix(v7)
+
+	assert.True(t, m.modules[6][22], "alignment pattern center at (6,22) should be dark")
+	assert.True(t, m.modules[4][22], "alignment pattern top edge at (4,22) should be dark")
+	assert.True(t, m.modules[8][22], "alignment pattern bottom edge at (8,22) should be dark")
+	assert.True(t, m.modules[6][20], "alignment pattern left edge at (6,20) should be dark")
+	assert.True(t, m.modules[6][24], "alignment pattern right edge at (6,24) should be dark")
+	assert.False(t, m.modules[5][21], "alignment pattern ring interior at (5,21) should be light")
+
+	assert.True(t, m.modules[22][6], "alignment pattern center at (22,6) should be dark")
+}
@@ -0,0 +1,119 @@
+// Package qr is a small, self-contained QR code encoder (byte mode,
+// error-correction level L, versions 1-20) good enough to hand a WireGuard
+// client config to a phone camera. It intentionally doesn't chase the
+// full QR spec (versions above 20, alternate EC levels, penalty-optimized
+// mask selection) - see tables.go and applyMask0 in matrix.go for what
+// that trades away.
+package qr
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+)
+
+// Code is a fully built, maskable QR code symbol.
+type Code struct {
+	m *matrix
+}
+
+// Encode builds a QR code for data in byte mode at error-correction
+// level L, choosing the smallest supported version (1-20) that fits.
+func Encode(data []byte) (*Code, error) {
+	v, err := chooseVersion(len(data))
+	if err != nil {
+		return nil, err
+	}
+	dataCodewords := encodeData(data, v)
+	codewords := interleave(dataCodewords, v)
+	m := newMatrix(v)
+	m.build(codewords)
+	return &Code{m: m}, nil
+}
+
+// Size returns the symbol's module width/height, not counting quiet zone.
+func (c *Code) Size() int {
+	return c.m.size
+}
+
+// At reports whether the module at (row, col) is dark.
+func (c *Code) At(row, col int) bool {
+	return c.m.modules[row][col]
+}
+
+// Image renders the code as a 1-bit-per-module image, each module drawn
+// as a scale x scale pixel block with a quiet-zone border of 4 modules,
+// as required for reliable scanning.
+func (c *Code) Image(scale int) image.Image {
+	const quietZone = 4
+	dim := (c.m.size + 2*quietZone) * scale
+	img := image.NewGray(image.Rect(0, 0, dim, dim))
+	white := color.Gray{Y: 0xFF}
+	for y := 0; y < dim; y++ {
+		for x := 0; x < dim; x++ {
+			img.SetGray(x, y, white)
+		}
+	}
+	black := color.Gray{Y: 0x00}
+	for r := 0; r < c.m.size; r++ {
+		for col := 0; col < c.m.size; col++ {
+			if !c.m.modules[r][col] {
+				continue
+			}
+			x0 := (col + quietZone) * scale
+			y0 := (r + quietZone) * scale
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetGray(x0+dx, y0+dy, black)
+				}
+			}
+		}
+	}
+	return img
+}
+
+// PNG renders the code as a PNG image with 8 pixels per module.
+func (c *Code) PNG() ([]byte, error) {
+	var b bytes.Buffer
+	if err := png.Encode(&b, c.Image(8)); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// ANSI renders the code as two-rows-per-character terminal output using
+// half-block characters, so it can be scanned straight out of a shell.
+func (c *Code) ANSI() string {
+	const quietZone = 2
+	size := c.m.size + 2*quietZone
+	at := func(r, col int) bool {
+		r -= quietZone
+		col -= quietZone
+		if r < 0 || col < 0 || r >= c.m.size || col >= c.m.size {
+			return false
+		}
+		return c.m.modules[r][col]
+	}
+
+	var b strings.Builder
+	for r := 0; r < size; r += 2 {
+		for col := 0; col < size; col++ {
+			top := at(r, col)
+			bottom := at(r+1, col)
+			switch {
+			case top && bottom:
+				b.WriteRune('█')
+			case top && !bottom:
+				b.WriteRune('▀')
+			case !top && bottom:
+				b.WriteRune('▄')
+			default:
+				b.WriteRune(' ')
+			}
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
@@ -0,0 +1,65 @@
+package qr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeFinderPatternCorners(t *testing.T) {
+	code, err := Encode([]byte("hello wgclient"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	// The finder pattern's center module is always dark.
+	assert.True(t, code.At(3, 3))
+	assert.True(t, code.At(3, code.Size()-4))
+	assert.True(t, code.At(code.Size()-4, 3))
+}
+
+func TestEncodeChoosesLargerVersionForMoreData(t *testing.T) {
+	small, err := Encode([]byte("short"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	big, err := Encode([]byte(wgConfigFixture))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Less(t, small.Size(), big.Size())
+}
+
+func TestEncodeTooLarge(t *testing.T) {
+	data := make([]byte, 10000)
+	_, err := Encode(data)
+	assert.Error(t, err)
+}
+
+func TestPNGAndANSIRender(t *testing.T) {
+	code, err := Encode([]byte(wgConfigFixture))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	png, err := code.PNG()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "\x89PNG\r\n\x1a\n", string(png[:8]))
+
+	ansi := code.ANSI()
+	assert.NotEmpty(t, ansi)
+	assert.Contains(t, ansi, "\n")
+}
+
+const wgConfigFixture = `[Interface]
+PrivateKey = cGFja2V0c29mZnJlc2hkYXRhZ29oZXJlMTIzND0=
+Address = 10.10.0.5/32, fd00::5/128
+DNS = 1.1.1.1,1.0.0.1
+
+[Peer]
+PublicKey = YW5vdGhlcmZha2VrZXlmb3J0ZXN0aW5ncHVycG9zZT0=
+Endpoint = vpn.example.com:51820
+PersistentKeepalive = 25
+AllowedIPs = 0.0.0.0/0, ::/0
+`
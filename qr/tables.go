@@ -0,0 +1,98 @@
+package qr
+
+// version describes the structure of a QR code symbol at error-correction
+// level L (the lowest level, chosen to maximize data capacity for our use
+// case: a scannable but otherwise unauthenticated config handoff).
+//
+// blockLens holds one entry per Reed-Solomon block, in data-codeword
+// length; versions 1-9, 11 and 13 split evenly (all entries equal), but
+// versions 10, 12 and 14-20 mix two block sizes that differ by one codeword, so
+// interleave() works off this slice rather than assuming a single length.
+// Versions above 20 would need both a bigger alignmentCoords table and
+// more version-info constants than are worth hardcoding for a wg-quick
+// config payload, so the table stops there: 861 data codewords is far
+// beyond any realistic multi-peer config, PresharedKey included.
+type version struct {
+	number          int
+	totalCodewords  int
+	ecPerBlock      int
+	blockLens       []int
+	alignmentCoords []int
+}
+
+func uniformBlocks(n, length int) []int {
+	lens := make([]int, n)
+	for i := range lens {
+		lens[i] = length
+	}
+	return lens
+}
+
+func mixedBlocks(nShort, shortLen, nLong int) []int {
+	lens := make([]int, 0, nShort+nLong)
+	lens = append(lens, uniformBlocks(nShort, shortLen)...)
+	lens = append(lens, uniformBlocks(nLong, shortLen+1)...)
+	return lens
+}
+
+var versions = []version{
+	{1, 26, 7, uniformBlocks(1, 19), nil},
+	{2, 44, 10, uniformBlocks(1, 34), []int{6, 18}},
+	{3, 70, 15, uniformBlocks(1, 55), []int{6, 22}},
+	{4, 100, 20, uniformBlocks(1, 80), []int{6, 26}},
+	{5, 134, 26, uniformBlocks(1, 108), []int{6, 30}},
+	{6, 172, 18, uniformBlocks(2, 68), []int{6, 34}},
+	{7, 196, 20, uniformBlocks(2, 78), []int{6, 22, 38}},
+	{8, 242, 24, uniformBlocks(2, 97), []int{6, 24, 42}},
+	{9, 292, 30, uniformBlocks(2, 116), []int{6, 26, 46}},
+	{10, 346, 18, mixedBlocks(2, 68, 2), []int{6, 28, 50}},
+	{11, 404, 20, uniformBlocks(4, 81), []int{6, 30, 54}},
+	{12, 466, 24, mixedBlocks(2, 92, 2), []int{6, 32, 58}},
+	{13, 532, 26, uniformBlocks(4, 107), []int{6, 34, 62}},
+	{14, 581, 30, mixedBlocks(3, 115, 1), []int{6, 26, 46, 66}},
+	{15, 655, 22, mixedBlocks(5, 87, 1), []int{6, 26, 48, 70}},
+	{16, 733, 24, mixedBlocks(5, 98, 1), []int{6, 26, 50, 74}},
+	{17, 815, 28, mixedBlocks(1, 107, 5), []int{6, 30, 54, 78}},
+	{18, 901, 30, mixedBlocks(5, 120, 1), []int{6, 30, 56, 82}},
+	{19, 991, 28, mixedBlocks(3, 113, 4), []int{6, 30, 58, 86}},
+	{20, 1085, 28, mixedBlocks(3, 107, 5), []int{6, 34, 62, 90}},
+}
+
+func (v version) size() int {
+	return 17 + 4*v.number
+}
+
+func (v version) dataCodewords() int {
+	total := 0
+	for _, l := range v.blockLens {
+		total += l
+	}
+	return total
+}
+
+// charCountBits is the length of the byte-mode character count indicator.
+func (v version) charCountBits() int {
+	if v.number < 10 {
+		return 8
+	}
+	return 16
+}
+
+// versionInfoBits holds the 18-bit BCH-encoded version info block for
+// versions 7-20 (no version info is encoded below version 7).
+var versionInfoBits = map[int]uint32{
+	7:  0x07C94,
+	8:  0x085BC,
+	9:  0x09A99,
+	10: 0x0A4D3,
+	11: 0x0BBF6,
+	12: 0x0C762,
+	13: 0x0D847,
+	14: 0x0E60D,
+	15: 0x0F928,
+	16: 0x10B78,
+	17: 0x1145D,
+	18: 0x12A17,
+	19: 0x13532,
+	20: 0x149A6,
+}
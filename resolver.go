@@ -0,0 +1,252 @@
+package wgclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// testTLSRootCAs lets tests point the tls:// resolver at a self-signed
+// stub server; nil (the default) uses the system trust store.
+var testTLSRootCAs *x509.CertPool
+
+// dialTimeout bounds bootstrap resolver connections; AllowedIPs resolution
+// happens at config-build time, not on the data path, so it can afford to
+// be conservative.
+const dialTimeout = 10 * time.Second
+
+// Resolver is the subset of *net.Resolver that UpdateAllowedIPs needs.
+// *net.Resolver already implements it.
+type Resolver interface {
+	LookupIP(ctx context.Context, network, host string) ([]net.IP, error)
+}
+
+// NewBootstrapResolver parses upstream into a Resolver for
+// Config.UpdateAllowedIPs. AllowedIPs is the routing surface the tunnel
+// exists to protect, so resolving it over plaintext DNS at config-build
+// time would leak exactly what the tunnel is meant to hide. Supported
+// forms:
+//
+//	tls://host:port    DNS-over-TLS, dialed with SNI set to host
+//	https://host/path  DNS-over-HTTPS (RFC 8484), POSTing DNS wire format
+//	tcp://host:port    plain DNS over TCP
+//	host:port          bare address, dialed directly with the Go resolver
+//	""                 the system resolver
+func NewBootstrapResolver(upstream string) (Resolver, error) {
+	if upstream == "" {
+		return net.DefaultResolver, nil
+	}
+
+	u, err := url.Parse(upstream)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				d := net.Dialer{Timeout: dialTimeout}
+				return d.DialContext(ctx, network, upstream)
+			},
+		}, nil
+	}
+
+	switch u.Scheme {
+	case "tcp":
+		host := u.Host
+		return &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				d := net.Dialer{Timeout: dialTimeout}
+				return d.DialContext(ctx, "tcp", host)
+			},
+		}, nil
+	case "tls":
+		host := u.Host
+		serverName := u.Hostname()
+		return &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+				cfg := &tls.Config{ServerName: serverName}
+				if testTLSRootCAs != nil {
+					cfg.RootCAs = testTLSRootCAs
+				}
+				d := tls.Dialer{Config: cfg, NetDialer: &net.Dialer{Timeout: dialTimeout}}
+				return d.DialContext(ctx, "tcp", host)
+			},
+		}, nil
+	case "https":
+		return &dohResolver{endpoint: upstream, client: &http.Client{Timeout: dialTimeout}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported resolver scheme %q in upstream %q", u.Scheme, upstream)
+	}
+}
+
+// dohResolver implements Resolver over DNS-over-HTTPS (RFC 8484), POSTing
+// a minimal hand-rolled DNS query message and parsing A/AAAA answers out
+// of the response. It only supports what UpdateAllowedIPs needs, not
+// general-purpose DNS.
+type dohResolver struct {
+	endpoint string
+	client   *http.Client
+}
+
+const (
+	dnsTypeA    = 1
+	dnsTypeAAAA = 28
+)
+
+func (d *dohResolver) LookupIP(ctx context.Context, network, host string) ([]net.IP, error) {
+	var qtypes []uint16
+	switch network {
+	case "ip4":
+		qtypes = []uint16{dnsTypeA}
+	case "ip6":
+		qtypes = []uint16{dnsTypeAAAA}
+	default:
+		qtypes = []uint16{dnsTypeA, dnsTypeAAAA}
+	}
+
+	var ips []net.IP
+	for _, qtype := range qtypes {
+		msg, err := dnsQuery(host, qtype)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.endpoint, bytes.NewReader(msg))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/dns-message")
+		req.Header.Set("Accept", "application/dns-message")
+		resp, err := d.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("doh query to %s: unexpected status %d", d.endpoint, resp.StatusCode)
+		}
+		answers, err := parseDNSAnswers(body)
+		if err != nil {
+			return nil, err
+		}
+		ips = append(ips, answers...)
+	}
+	return ips, nil
+}
+
+// dnsQuery builds a minimal DNS wire-format query for name/qtype. The ID
+// is left at 0, matching the RFC 8484 guidance that DoH messages SHOULD
+// use ID 0 so responses are cacheable independent of the request.
+func dnsQuery(name string, qtype uint16) ([]byte, error) {
+	var b bytes.Buffer
+	// header: ID, flags (RD), QDCOUNT=1, ANCOUNT/NSCOUNT/ARCOUNT=0
+	b.Write([]byte{0, 0, 0x01, 0x00, 0, 1, 0, 0, 0, 0, 0, 0})
+	if err := writeDNSName(&b, name); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(&b, binary.BigEndian, qtype); err != nil {
+		return nil, err
+	}
+	if err := binary.Write(&b, binary.BigEndian, uint16(1)); err != nil { // QCLASS IN
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func writeDNSName(b *bytes.Buffer, name string) error {
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return fmt.Errorf("invalid DNS label in name %q", name)
+		}
+		b.WriteByte(byte(len(label)))
+		b.WriteString(label)
+	}
+	b.WriteByte(0)
+	return nil
+}
+
+// parseDNSAnswers extracts A/AAAA records from a DNS wire-format response.
+func parseDNSAnswers(data []byte) ([]net.IP, error) {
+	if len(data) < 12 {
+		return nil, errors.New("dns response too short")
+	}
+	qdcount := binary.BigEndian.Uint16(data[4:6])
+	ancount := binary.BigEndian.Uint16(data[6:8])
+
+	off := 12
+	for i := 0; i < int(qdcount); i++ {
+		var err error
+		off, err = skipDNSName(data, off)
+		if err != nil {
+			return nil, err
+		}
+		off += 4 // QTYPE + QCLASS
+	}
+
+	var ips []net.IP
+	for i := 0; i < int(ancount); i++ {
+		var err error
+		off, err = skipDNSName(data, off)
+		if err != nil {
+			return nil, err
+		}
+		if off+10 > len(data) {
+			return nil, errors.New("dns response truncated in answer header")
+		}
+		rtype := binary.BigEndian.Uint16(data[off : off+2])
+		rdlength := binary.BigEndian.Uint16(data[off+8 : off+10])
+		off += 10
+		if off+int(rdlength) > len(data) {
+			return nil, errors.New("dns response truncated in answer data")
+		}
+		rdata := data[off : off+int(rdlength)]
+		switch rtype {
+		case dnsTypeA:
+			if len(rdata) == net.IPv4len {
+				ips = append(ips, net.IP(rdata))
+			}
+		case dnsTypeAAAA:
+			if len(rdata) == net.IPv6len {
+				ips = append(ips, net.IP(rdata))
+			}
+		}
+		off += int(rdlength)
+	}
+	return ips, nil
+}
+
+// skipDNSName advances past a DNS name starting at off, stopping after a
+// compression pointer if one is reached (the pointer is not followed), and
+// returns the offset just past the name.
+func skipDNSName(data []byte, off int) (int, error) {
+	for {
+		if off >= len(data) {
+			return 0, errors.New("dns response truncated in name")
+		}
+		l := int(data[off])
+		switch {
+		case l == 0:
+			return off + 1, nil
+		case l&0xC0 == 0xC0:
+			if off+1 >= len(data) {
+				return 0, errors.New("dns response truncated in name pointer")
+			}
+			return off + 2, nil
+		default:
+			off += 1 + l
+		}
+	}
+}
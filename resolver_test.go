@@ -0,0 +1,207 @@
+package wgclient
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// encodeDNSAnswer builds a minimal wire-format DNS response answering the
+// single question in query with ip, for use by the stub servers below.
+func encodeDNSAnswer(t *testing.T, query []byte, ip net.IP) []byte {
+	t.Helper()
+	qdcount := binary.BigEndian.Uint16(query[4:6])
+	if !assert.Equal(t, uint16(1), qdcount) {
+		t.FailNow()
+	}
+	off, err := skipDNSName(query, 12)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	off += 4 // QTYPE + QCLASS
+
+	var b bytes.Buffer
+	b.Write(query[:2])                                        // ID, echoed
+	b.Write([]byte{0x81, 0x80})                                // flags: response, RD+RA
+	b.Write(query[4:6])                                        // QDCOUNT
+	b.Write([]byte{0, 1, 0, 0, 0, 0})                           // ANCOUNT=1, NSCOUNT=0, ARCOUNT=0
+	b.Write(query[12:off])                                     // question section, verbatim
+
+	b.Write([]byte{0xC0, 0x0C}) // NAME: pointer to the question name at offset 12
+	rtype := uint16(dnsTypeA)
+	rdata := ip.To4()
+	if rdata == nil {
+		rtype = dnsTypeAAAA
+		rdata = ip.To16()
+	}
+	binary.Write(&b, binary.BigEndian, rtype)
+	binary.Write(&b, binary.BigEndian, uint16(1)) // CLASS IN
+	binary.Write(&b, binary.BigEndian, uint32(60))
+	binary.Write(&b, binary.BigEndian, uint16(len(rdata)))
+	b.Write(rdata)
+	return b.Bytes()
+}
+
+func TestParseDNSAnswers(t *testing.T) {
+	q, err := dnsQuery("example.com", dnsTypeA)
+	if !assert.NoError(t, err) {
+		return
+	}
+	resp := encodeDNSAnswer(t, q, net.ParseIP("93.184.216.34"))
+	ips, err := parseDNSAnswers(resp)
+	if !assert.NoError(t, err) {
+		return
+	}
+	if !assert.Len(t, ips, 1) {
+		return
+	}
+	assert.Equal(t, "93.184.216.34", ips[0].String())
+}
+
+// TestDoHResolver exercises dohResolver against a stub DoH server.
+func TestDoHResolver(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query, err := io.ReadAll(r.Body)
+		if !assert.NoError(t, err) {
+			return
+		}
+		w.Header().Set("Content-Type", "application/dns-message")
+		w.Write(encodeDNSAnswer(t, query, net.ParseIP("203.0.113.7")))
+	}))
+	defer srv.Close()
+
+	r := &dohResolver{endpoint: srv.URL, client: srv.Client()}
+	ips, err := r.LookupIP(context.Background(), "ip4", "example.com")
+	if !assert.NoError(t, err) {
+		return
+	}
+	if !assert.Len(t, ips, 1) {
+		return
+	}
+	assert.Equal(t, "203.0.113.7", ips[0].String())
+}
+
+func TestNewBootstrapResolverScheme(t *testing.T) {
+	r, err := NewBootstrapResolver("https://dns.google/dns-query")
+	if !assert.NoError(t, err) {
+		return
+	}
+	doh, ok := r.(*dohResolver)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, "https://dns.google/dns-query", doh.endpoint)
+}
+
+// selfSignedCert mints an in-memory cert/key for IP so a stub DoT server
+// can terminate TLS without a real CA.
+func selfSignedCert(t *testing.T, ip net.IP) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: ip.String()},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{ip},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	cert, err := x509.ParseCertificate(der)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	testTLSRootCAs = pool
+	t.Cleanup(func() { testTLSRootCAs = nil })
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// TestDoTResolver exercises the tls:// path against a stub DoT server
+// speaking DNS-over-TCP framing (2-byte length prefix) under TLS.
+func TestDoTResolver(t *testing.T) {
+	cert := selfSignedCert(t, net.ParseIP("127.0.0.1"))
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer ln.Close()
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var lenBuf [2]byte
+		if _, err := io.ReadFull(conn, lenBuf[:]); err != nil {
+			return
+		}
+		query := make([]byte, binary.BigEndian.Uint16(lenBuf[:]))
+		if _, err := io.ReadFull(conn, query); err != nil {
+			return
+		}
+		resp := encodeDNSAnswer(t, query, net.ParseIP("198.51.100.9"))
+		binary.Write(conn, binary.BigEndian, uint16(len(resp)))
+		conn.Write(resp)
+	}()
+
+	r, err := NewBootstrapResolver("tls://" + ln.Addr().String())
+	if !assert.NoError(t, err) {
+		return
+	}
+	ips, err := r.LookupIP(context.Background(), "ip4", "example.com")
+	if !assert.NoError(t, err) {
+		return
+	}
+	if !assert.Len(t, ips, 1) {
+		return
+	}
+	assert.Equal(t, "198.51.100.9", ips[0].String())
+}
+
+func TestNewBootstrapResolver(t *testing.T) {
+	t.Run("empty uses system resolver", func(t *testing.T) {
+		r, err := NewBootstrapResolver("")
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Equal(t, net.DefaultResolver, r)
+	})
+
+	t.Run("bare ip:port falls back to legacy dial", func(t *testing.T) {
+		r, err := NewBootstrapResolver("1.1.1.1:53")
+		assert.NoError(t, err)
+		assert.NotNil(t, r)
+	})
+
+	t.Run("unsupported scheme", func(t *testing.T) {
+		_, err := NewBootstrapResolver("ftp://example.com")
+		assert.Error(t, err)
+	})
+}
@@ -12,7 +12,6 @@ import (
 	"sort"
 	"strings"
 	"text/template"
-	"time"
 
 	"github.com/rs/zerolog/log"
 )
@@ -31,15 +30,68 @@ func RenderClientConfig(renderContext TemplateContext) ([]byte, error) {
 	return b.Bytes(), nil
 }
 
+//go:embed server_config_template.conf
+var serverConfigTemplateData []byte
+
+var serverConfigTemplate = template.Must(template.New("server_conf").Parse(string(serverConfigTemplateData)))
+
+// RenderServerConfig renders the server-side wg0.conf: its own [Interface]
+// section plus one [Peer] block per user, the symmetric counterpart to
+// RenderClientConfig.
+func RenderServerConfig(renderContext ServerTemplateContext) ([]byte, error) {
+	var b bytes.Buffer
+	err := serverConfigTemplate.Execute(&b, renderContext)
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
 // TemplateContext is a template for rending a single end user client config file.
 type TemplateContext struct {
-	PrivateKey    string
-	PeerPublicKey string
-	PeerEndpoint  string
-	NetPrefix     string
-	NetSuffix     string
-	DNS           string
-	AllowedIPS    string
+	PrivateKey string
+	Addresses  []Address
+	DNS        string
+	Peers      []Peer
+}
+
+// AddressLine joins Addresses into the comma-separated value of the
+// [Interface] Address line, e.g. "10.0.0.5/32, fd00::5/128".
+func (t TemplateContext) AddressLine() string {
+	return addressLine(t.Addresses)
+}
+
+// addressLine joins addrs into a comma-separated [Interface] Address value,
+// shared by TemplateContext and ServerTemplateContext.
+func addressLine(addrs []Address) string {
+	s := make([]string, len(addrs))
+	for i, a := range addrs {
+		s[i] = a.String()
+	}
+	return strings.Join(s, ", ")
+}
+
+// Address is a single interface address with its CIDR width, e.g.
+// 10.0.0.5/32 or fd00::5/128. Unlike the old hardcoded "/32 only" model,
+// Address carries its width explicitly so v4 and v6 entries can coexist.
+type Address struct {
+	IP     string
+	Prefix int
+}
+
+func (a Address) String() string {
+	return fmt.Sprintf("%s/%d", a.IP, a.Prefix)
+}
+
+// Peer is a single [Peer] block in a rendered client config. A config may
+// carry more than one, e.g. a primary plus a fallback endpoint, or
+// split-tunnel peers each scoped to their own AllowedIPs.
+type Peer struct {
+	PublicKey           string
+	Endpoint            string
+	AllowedIPs          string
+	PersistentKeepalive int
+	PresharedKey        string
 }
 
 type Config struct {
@@ -49,69 +101,142 @@ type Config struct {
 	DNS             []string
 	ServerPublicKey string
 	Interfaces      Interfaces
+	DisableIPv4     bool
+	DisableIPv6     bool
+
+	// AllowList/DenyList are CIDR (or bare IP) rules filtering which
+	// resolved AllowedIPs entries and peers are let through; see
+	// NewAllowListFromConfig. UserAllowList/UserDenyList key the same
+	// rules by the Users map key for per-user overrides.
+	AllowList     []string
+	DenyList      []string
+	UserAllowList map[string][]string
+	UserDenyList  map[string][]string
 }
 
-func (c *Config) UpdateAllowedIPs(ctx context.Context, nameserver string) error {
-	r := net.DefaultResolver
-	if nameserver != "" {
-		r = &net.Resolver{
-			PreferGo: true,
-			Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
-				d := net.Dialer{
-					Timeout: time.Second * 10,
-				}
-				return d.DialContext(ctx, network, nameserver)
-			},
-		}
+// dnsFamilies returns the LookupIP network names to resolve, honoring
+// DisableIPv4/DisableIPv6.
+func (c Config) dnsFamilies() []string {
+	var families []string
+	if !c.DisableIPv4 {
+		families = append(families, "ip4")
+	}
+	if !c.DisableIPv6 {
+		families = append(families, "ip6")
+	}
+	return families
+}
+
+// UpdateAllowedIPs resolves c.DNSNames through upstream and merges the
+// results into c.AllowedIPs. upstream is a URL-style resolver spec
+// (tls://host:853, https://host/dns-query, tcp://host:53) or a bare
+// "ip:port", in which case it's dialed directly with the Go resolver as
+// before; "" uses the system resolver. See NewBootstrapResolver.
+func (c *Config) UpdateAllowedIPs(ctx context.Context, upstream string) error {
+	r, err := NewBootstrapResolver(upstream)
+	if err != nil {
+		return err
 	}
 	var res []string
 	for _, n := range c.DNSNames {
-		ips, err := r.LookupIP(ctx, "ip4", n)
-		if err != nil {
-			log.Error().Str("dns_name", n).Err(err).Msg("")
-			return err
+		var resolved int
+		for _, family := range c.dnsFamilies() {
+			ips, err := r.LookupIP(ctx, family, n)
+			if err != nil {
+				if isNoRecordError(err) {
+					continue
+				}
+				log.Error().Str("dns_name", n).Str("family", family).Err(err).Msg("")
+				return err
+			}
+			resolved += len(ips)
+			for _, ip := range ips {
+				res = append(res, allowedIPEntry(ip))
+			}
 		}
-		if len(ips) == 0 {
+		if resolved == 0 {
 			log.Error().Str("dns_name", n).Msg("no DNS resolver response for dns name")
 			return errors.New("no DNS resolver response for dns name")
 		}
-		for _, ip := range ips {
-			res = append(res, fmt.Sprintf("%s/32", ip))
-		}
 	}
+	al, err := NewAllowListFromConfig(*c)
+	if err != nil {
+		return err
+	}
+	res = al.FilterAllowedIPs(res)
 	res = append(res, c.AllowedIPs...)
 	sort.Strings(res)
 	c.AllowedIPs = compact(res)
 	return nil
 }
 
+// isNoRecordError reports whether err is the resolver's way of saying a
+// name has no address record for the family that was queried (e.g. an
+// A-only host looked up for "ip6"), as opposed to a real resolution
+// failure. Most hostnames are A-only, so treating this as "0 resolved for
+// this family" rather than aborting the whole lookup keeps IPv6 opt-out
+// by default from being necessary.
+func isNoRecordError(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// allowedIPEntry formats ip as a /32 host route, or /128 for IPv6.
+func allowedIPEntry(ip net.IP) string {
+	if ip.To4() != nil {
+		return fmt.Sprintf("%s/32", ip)
+	}
+	return fmt.Sprintf("%s/128", ip)
+}
+
 func (c *Config) UpdateAllowedIPsWithDefaultResolver(ctx context.Context) error {
 	var res []string
 	for _, n := range c.DNSNames {
-
-		ips, err := net.DefaultResolver.LookupIP(ctx, "ip4", n)
-		if err != nil {
-			log.Error().Str("dns_name", n).Err(err).Msg("")
-			return err
+		var resolved int
+		for _, family := range c.dnsFamilies() {
+			ips, err := net.DefaultResolver.LookupIP(ctx, family, n)
+			if err != nil {
+				if isNoRecordError(err) {
+					continue
+				}
+				log.Error().Str("dns_name", n).Str("family", family).Err(err).Msg("")
+				return err
+			}
+			resolved += len(ips)
+			for _, ip := range ips {
+				res = append(res, allowedIPEntry(ip))
+			}
 		}
-		if len(ips) == 0 {
+		if resolved == 0 {
 			log.Error().Str("dns_name", n).Msg("no DNS resolver response for dns name")
 			return errors.New("no DNS resolver response for dns name")
 		}
-		for _, ip := range ips {
-			res = append(res, fmt.Sprintf("%s/32", ip))
-		}
 	}
+	al, err := NewAllowListFromConfig(*c)
+	if err != nil {
+		return err
+	}
+	res = al.FilterAllowedIPs(res)
 	res = append(res, c.AllowedIPs...)
 	sort.Strings(res)
 	c.AllowedIPs = compact(res)
 	return nil
 }
 
-func (c Config) AllClientConfigs() ClientConfigs {
-	var cc ClientConfigs
+// AllClientConfigs enumerates every client-config variant (per interface,
+// plus a combined multi-interface variant) that's actually renderable for
+// user: one with at least one surviving Peer once al's allow/deny rules
+// are applied. al may be nil, in which case no variant is filtered out.
+func (c Config) AllClientConfigs(user string, al *AllowList) (ClientConfigs, error) {
+	var all []int
 	for i := range c.Interfaces {
-		cc = append(cc,
+		all = append(all, i)
+	}
+	sort.Ints(all)
+
+	var candidates ClientConfigs
+	for _, i := range all {
+		candidates = append(candidates,
 			ClientConfig{
 				IF:       i,
 				NoDNS:    false,
@@ -129,26 +254,202 @@ func (c Config) AllClientConfigs() ClientConfigs {
 			},
 		)
 	}
-	return cc
+	// When there's more than one interface, also consider a combined
+	// variant that folds every other interface's peer into the first
+	// one's config, e.g. a primary + fallback endpoint in a single file.
+	if len(all) > 1 {
+		candidates = append(candidates, ClientConfig{
+			IF:         all[0],
+			CombineIFs: all[1:],
+		})
+	}
+
+	var cc ClientConfigs
+	for _, candidate := range candidates {
+		peers, err := c.PeersForUser(user, candidate, al)
+		if err != nil {
+			return nil, err
+		}
+		if len(peers) == 0 {
+			continue
+		}
+		cc = append(cc, candidate)
+	}
+	return cc, nil
 }
 
-// Interface .
+// Interface is one WireGuard server-side interface (e.g. wg0, wg1) that a
+// client can be peered with.
 type Interface struct {
-	Endpoint string
-	Prefix   string
+	Endpoint            string
+	Prefix              string // IPv4 address prefix, e.g. "10.0.0."
+	Prefix6             string // IPv6 address prefix, e.g. "fd00::"; empty disables v6 for this interface
+	PublicKey           string
+	AllowedIPs          []string
+	PersistentKeepalive int
+	PresharedKey        string
+
+	// The rest are only needed to render this interface's own wg0.conf via
+	// RenderServerConfig; they don't appear in any client-facing [Peer].
+	PrivateKey string
+	ListenPort int
+	PostUp     []string // wg-quick PostUp lines, e.g. iptables/nftables NAT rules
+	PostDown   []string // wg-quick PostDown lines, undoing PostUp
+}
+
+// peer renders i as the Peer seen by a client, falling back to
+// defaultKeepalive when the interface doesn't set its own.
+func (i Interface) peer(defaultKeepalive int) Peer {
+	keepalive := i.PersistentKeepalive
+	if keepalive == 0 {
+		keepalive = defaultKeepalive
+	}
+	return Peer{
+		PublicKey:           i.PublicKey,
+		Endpoint:            i.Endpoint,
+		AllowedIPs:          strings.Join(i.AllowedIPs, ", "),
+		PersistentKeepalive: keepalive,
+		PresharedKey:        i.PresharedKey,
+	}
 }
 
 // ClientConfig .
 type ClientConfig struct {
-	IF       int  `json:"if"`        // interface 0=wg0 1=wg1...
-	NoDNS    bool `json:"no_dns"`    // comment out the DNS =  line
-	RouteDNS bool `json:"route_dns"` // Append the DNS servers to AllowedIPs line
+	IF         int   `json:"if"`                    // interface 0=wg0 1=wg1...
+	CombineIFs []int `json:"combine_ifs,omitempty"` // additional interfaces whose peers are folded into this config
+	NoDNS      bool  `json:"no_dns"`                // comment out the DNS =  line
+	RouteDNS   bool  `json:"route_dns"`              // Append the DNS servers to AllowedIPs line
+}
+
+// PeersForClientConfig resolves cc.IF and cc.CombineIFs into the ordered
+// list of Peer entries for a rendered client config.
+func (c Config) PeersForClientConfig(cc ClientConfig) ([]Peer, error) {
+	ifs := append([]int{cc.IF}, cc.CombineIFs...)
+	peers := make([]Peer, 0, len(ifs))
+	for _, i := range ifs {
+		iface, ok := c.Interfaces[i]
+		if !ok {
+			return nil, fmt.Errorf("no interface %d in config", i)
+		}
+		peers = append(peers, iface.peer(25))
+	}
+	return peers, nil
+}
+
+// ServerPeer is one [Peer] block in a rendered server wg0.conf, one per
+// user on that interface.
+type ServerPeer struct {
+	Name         string // user's Name, rendered as a "# Name" comment above the block
+	PublicKey    string
+	AllowedIPs   string
+	PresharedKey string
+}
+
+// ServerTemplateContext is a template for rendering a single server-side
+// wg0.conf, the symmetric counterpart to TemplateContext.
+type ServerTemplateContext struct {
+	PrivateKey string
+	ListenPort int
+	Addresses  []Address
+	PostUp     []string
+	PostDown   []string
+	Peers      []ServerPeer
+}
+
+// AddressLine joins Addresses into the comma-separated value of the
+// [Interface] Address line.
+func (t ServerTemplateContext) AddressLine() string {
+	return addressLine(t.Addresses)
+}
+
+// userUsesInterface reports whether any of u's client configs are served
+// off interface i, directly or as part of a combined config.
+func userUsesInterface(u User, i int) bool {
+	for _, cc := range u.ClientConfigs {
+		if cc.IF == i {
+			return true
+		}
+		for _, combined := range cc.CombineIFs {
+			if combined == i {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ServerPeers builds the [Peer] blocks for interface i's wg0.conf: one per
+// user configured against that interface, in a stable order keyed by the
+// Users map's suffix key. AllowedIPs is the interface's address prefix
+// plus that user's suffix, e.g. Prefix "10.0.0." and suffix "2" gives
+// "10.0.0.2/32".
+func (c Config) ServerPeers(i int) ([]ServerPeer, error) {
+	iface, ok := c.Interfaces[i]
+	if !ok {
+		return nil, fmt.Errorf("no interface %d in config", i)
+	}
+	suffixes := make([]string, 0, len(c.Users))
+	for suffix := range c.Users {
+		suffixes = append(suffixes, suffix)
+	}
+	sort.Strings(suffixes)
+
+	var peers []ServerPeer
+	for _, suffix := range suffixes {
+		u := c.Users[suffix]
+		if !userUsesInterface(u, i) {
+			continue
+		}
+		peers = append(peers, ServerPeer{
+			Name:         u.Name,
+			PublicKey:    u.Pub,
+			AllowedIPs:   fmt.Sprintf("%s%s/32", iface.Prefix, suffix),
+			PresharedKey: iface.PresharedKey,
+		})
+	}
+	return peers, nil
+}
+
+// ServerTemplateContextForInterface builds the ServerTemplateContext for
+// interface i's wg0.conf: the interface's own [Interface] section plus a
+// ServerPeers block per user. The server itself is always host "1" on the
+// interface's prefix, e.g. Prefix "10.0.0." renders Address 10.0.0.1/32.
+func (c Config) ServerTemplateContextForInterface(i int) (ServerTemplateContext, error) {
+	iface, ok := c.Interfaces[i]
+	if !ok {
+		return ServerTemplateContext{}, fmt.Errorf("no interface %d in config", i)
+	}
+	peers, err := c.ServerPeers(i)
+	if err != nil {
+		return ServerTemplateContext{}, err
+	}
+
+	var addrs []Address
+	if iface.Prefix != "" {
+		addrs = append(addrs, Address{IP: iface.Prefix + "1", Prefix: 32})
+	}
+	if iface.Prefix6 != "" {
+		addrs = append(addrs, Address{IP: iface.Prefix6 + "1", Prefix: 128})
+	}
+
+	return ServerTemplateContext{
+		PrivateKey: iface.PrivateKey,
+		ListenPort: iface.ListenPort,
+		Addresses:  addrs,
+		PostUp:     iface.PostUp,
+		PostDown:   iface.PostDown,
+		Peers:      peers,
+	}, nil
 }
 
 func (w User) Filename(cc ClientConfig) string {
 	var ib strings.Builder
 	ib.WriteString("_")
 	ib.WriteString(fmt.Sprint(cc.IF))
+	for _, combined := range cc.CombineIFs {
+		ib.WriteString("+")
+		ib.WriteString(fmt.Sprint(combined))
+	}
 	if cc.NoDNS {
 		ib.WriteString("n")
 	}
@@ -2,6 +2,8 @@ package wgclient
 
 import (
 	"context"
+	"errors"
+	"net"
 	"testing"
 
 	_ "embed"
@@ -73,13 +75,17 @@ func TestReadConfig(t *testing.T) {
 
 func TestRenderConfig(t *testing.T) {
 	c := TemplateContext{
-		PrivateKey:    "priv-key",
-		PeerPublicKey: "peer-pub-key",
-		PeerEndpoint:  "peer-endpoint",
-		NetPrefix:     "99.99.99.",
-		NetSuffix:     "100",
-		DNS:           "1,2",
-		AllowedIPS:    "1.1.1.1/32",
+		PrivateKey: "priv-key",
+		Addresses:  []Address{{IP: "99.99.99.100", Prefix: 32}},
+		DNS:        "1,2",
+		Peers: []Peer{
+			{
+				PublicKey:           "peer-pub-key",
+				Endpoint:            "peer-endpoint",
+				AllowedIPs:          "1.1.1.1/32",
+				PersistentKeepalive: 25,
+			},
+		},
 	}
 
 	t.Run("render1", func(t *testing.T) {
@@ -98,6 +104,7 @@ PublicKey = peer-pub-key
 Endpoint = peer-endpoint
 PersistentKeepalive = 25
 AllowedIPs = 1.1.1.1/32
+
 `, string(data))
 	})
 
@@ -118,10 +125,309 @@ PublicKey = peer-pub-key
 Endpoint = peer-endpoint
 PersistentKeepalive = 25
 AllowedIPs = 1.1.1.1/32
+
+`, string(data))
+	})
+
+	t.Run("multi-peer", func(t *testing.T) {
+		c := c
+		c.Peers = append(c.Peers, Peer{
+			PublicKey:  "fallback-pub-key",
+			Endpoint:   "fallback-endpoint",
+			AllowedIPs: "0.0.0.0/0",
+		})
+		data, err := RenderClientConfig(c)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, `[Interface]
+PrivateKey = priv-key
+Address = 99.99.99.100/32
+DNS = 1,2
+
+[Peer]
+PublicKey = peer-pub-key
+Endpoint = peer-endpoint
+PersistentKeepalive = 25
+AllowedIPs = 1.1.1.1/32
+
+[Peer]
+PublicKey = fallback-pub-key
+Endpoint = fallback-endpoint
+AllowedIPs = 0.0.0.0/0
+
+`, string(data))
+	})
+
+	t.Run("dual-stack", func(t *testing.T) {
+		c := c
+		c.Addresses = []Address{
+			{IP: "99.99.99.100", Prefix: 32},
+			{IP: "fd00::100", Prefix: 128},
+		}
+		data, err := RenderClientConfig(c)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, `[Interface]
+PrivateKey = priv-key
+Address = 99.99.99.100/32, fd00::100/128
+DNS = 1,2
+
+[Peer]
+PublicKey = peer-pub-key
+Endpoint = peer-endpoint
+PersistentKeepalive = 25
+AllowedIPs = 1.1.1.1/32
+
+`, string(data))
+	})
+}
+
+func TestPeersForClientConfig(t *testing.T) {
+	conf := Config{
+		Interfaces: Interfaces{
+			0: {Endpoint: "primary:51820", PublicKey: "primary-pub", AllowedIPs: []string{"10.0.0.0/24"}},
+			1: {Endpoint: "fallback:51820", PublicKey: "fallback-pub", AllowedIPs: []string{"10.0.1.0/24"}},
+		},
+	}
+
+	t.Run("single interface", func(t *testing.T) {
+		peers, err := conf.PeersForClientConfig(ClientConfig{IF: 0})
+		if !assert.NoError(t, err) {
+			return
+		}
+		assert.Len(t, peers, 1)
+		assert.Equal(t, "primary-pub", peers[0].PublicKey)
+		assert.Equal(t, 25, peers[0].PersistentKeepalive)
+	})
+
+	t.Run("combined interfaces", func(t *testing.T) {
+		peers, err := conf.PeersForClientConfig(ClientConfig{IF: 0, CombineIFs: []int{1}})
+		if !assert.NoError(t, err) {
+			return
+		}
+		if !assert.Len(t, peers, 2) {
+			return
+		}
+		assert.Equal(t, "primary-pub", peers[0].PublicKey)
+		assert.Equal(t, "fallback-pub", peers[1].PublicKey)
+	})
+
+	t.Run("unknown interface", func(t *testing.T) {
+		_, err := conf.PeersForClientConfig(ClientConfig{IF: 9})
+		assert.Error(t, err)
+	})
+}
+
+func TestAllClientConfigs(t *testing.T) {
+	conf := Config{
+		Interfaces: Interfaces{
+			0: {Endpoint: "primary:51820", PublicKey: "primary-pub", AllowedIPs: []string{"10.0.0.0/24"}},
+			1: {Endpoint: "fallback:51820", PublicKey: "fallback-pub", AllowedIPs: []string{"10.1.0.0/24"}},
+		},
+	}
+
+	t.Run("no allow list", func(t *testing.T) {
+		cc, err := conf.AllClientConfigs("1", nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		// 3 variants per interface plus the combined variant.
+		assert.Len(t, cc, 7)
+	})
+
+	t.Run("allow list drops a denied interface's solo variants", func(t *testing.T) {
+		al, err := NewAllowListFromConfig(Config{DenyList: []string{"10.1.0.0/16"}})
+		if !assert.NoError(t, err) {
+			return
+		}
+		cc, err := conf.AllClientConfigs("1", al)
+		if !assert.NoError(t, err) {
+			return
+		}
+		// Interface 1's 3 solo variants are dropped since its only peer is
+		// entirely denied; the combined variant survives on interface 0's
+		// peer alone.
+		assert.Len(t, cc, 4)
+		for _, c := range cc {
+			assert.NotEqual(t, 1, c.IF, "interface 1 is entirely denied and should not appear on its own")
+		}
+	})
+}
+
+func TestRenderServerConfig(t *testing.T) {
+	c := ServerTemplateContext{
+		PrivateKey: "server-priv",
+		ListenPort: 51820,
+		Addresses:  []Address{{IP: "10.0.0.1", Prefix: 32}},
+		Peers: []ServerPeer{
+			{Name: "alice", PublicKey: "alicepub", AllowedIPs: "10.0.0.2/32"},
+		},
+	}
+
+	t.Run("render", func(t *testing.T) {
+		data, err := RenderServerConfig(c)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, `[Interface]
+PrivateKey = server-priv
+Address = 10.0.0.1/32
+ListenPort = 51820
+
+[Peer]
+# alice
+PublicKey = alicepub
+AllowedIPs = 10.0.0.2/32
+
+`, string(data))
+	})
+
+	t.Run("postup-postdown", func(t *testing.T) {
+		c := c
+		c.PostUp = []string{"iptables -A FORWARD -i wg0 -j ACCEPT"}
+		c.PostDown = []string{"iptables -D FORWARD -i wg0 -j ACCEPT"}
+		data, err := RenderServerConfig(c)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, `[Interface]
+PrivateKey = server-priv
+Address = 10.0.0.1/32
+ListenPort = 51820
+PostUp = iptables -A FORWARD -i wg0 -j ACCEPT
+PostDown = iptables -D FORWARD -i wg0 -j ACCEPT
+
+[Peer]
+# alice
+PublicKey = alicepub
+AllowedIPs = 10.0.0.2/32
+
+`, string(data))
+	})
+
+	t.Run("multi-peer with preshared key", func(t *testing.T) {
+		c := c
+		c.Peers = append(c.Peers, ServerPeer{
+			PublicKey:    "bobpub",
+			AllowedIPs:   "10.0.0.3/32",
+			PresharedKey: "psk",
+		})
+		data, err := RenderServerConfig(c)
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		assert.Equal(t, `[Interface]
+PrivateKey = server-priv
+Address = 10.0.0.1/32
+ListenPort = 51820
+
+[Peer]
+# alice
+PublicKey = alicepub
+AllowedIPs = 10.0.0.2/32
+
+[Peer]
+PublicKey = bobpub
+PresharedKey = psk
+AllowedIPs = 10.0.0.3/32
+
 `, string(data))
 	})
 }
 
+func TestServerPeers(t *testing.T) {
+	conf := Config{
+		Interfaces: Interfaces{
+			0: {Prefix: "10.0.0."},
+			1: {Prefix: "10.0.1."},
+		},
+		Users: Users{
+			"2": {Name: "alice", Pub: "alicepub", ClientConfigs: ClientConfigs{{IF: 0}}},
+			"3": {Name: "bob", Pub: "bobpub", ClientConfigs: ClientConfigs{{IF: 1}}},
+			"4": {Name: "carol", Pub: "carolpub", ClientConfigs: ClientConfigs{{IF: 0, CombineIFs: []int{1}}}},
+		},
+	}
+
+	t.Run("interface 0 includes direct and combined users", func(t *testing.T) {
+		peers, err := conf.ServerPeers(0)
+		if !assert.NoError(t, err) {
+			return
+		}
+		if !assert.Len(t, peers, 2) {
+			return
+		}
+		assert.Equal(t, "alice", peers[0].Name)
+		assert.Equal(t, "10.0.0.2/32", peers[0].AllowedIPs)
+		assert.Equal(t, "carol", peers[1].Name)
+		assert.Equal(t, "10.0.0.4/32", peers[1].AllowedIPs)
+	})
+
+	t.Run("interface 1 includes direct and combined users", func(t *testing.T) {
+		peers, err := conf.ServerPeers(1)
+		if !assert.NoError(t, err) {
+			return
+		}
+		if !assert.Len(t, peers, 2) {
+			return
+		}
+		assert.Equal(t, "bob", peers[0].Name)
+		assert.Equal(t, "10.0.1.3/32", peers[0].AllowedIPs)
+		assert.Equal(t, "carol", peers[1].Name)
+		assert.Equal(t, "10.0.1.4/32", peers[1].AllowedIPs)
+	})
+
+	t.Run("unknown interface", func(t *testing.T) {
+		_, err := conf.ServerPeers(9)
+		assert.Error(t, err)
+	})
+}
+
+func TestServerTemplateContextForInterface(t *testing.T) {
+	conf := Config{
+		Interfaces: Interfaces{
+			0: {Prefix: "10.0.0.", Prefix6: "fd00::", PrivateKey: "server-priv", ListenPort: 51820},
+		},
+		Users: Users{
+			"2": {Name: "alice", Pub: "alicepub", ClientConfigs: ClientConfigs{{IF: 0}}},
+		},
+	}
+
+	ctx, err := conf.ServerTemplateContextForInterface(0)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "server-priv", ctx.PrivateKey)
+	assert.Equal(t, 51820, ctx.ListenPort)
+	assert.Equal(t, "10.0.0.1/32, fd00::1/128", ctx.AddressLine())
+	if !assert.Len(t, ctx.Peers, 1) {
+		return
+	}
+	assert.Equal(t, "alice", ctx.Peers[0].Name)
+
+	t.Run("unknown interface", func(t *testing.T) {
+		_, err := conf.ServerTemplateContextForInterface(9)
+		assert.Error(t, err)
+	})
+}
+
+func TestAllowedIPEntry(t *testing.T) {
+	assert.Equal(t, "1.1.1.1/32", allowedIPEntry(net.ParseIP("1.1.1.1")))
+	assert.Equal(t, "2606:4700:4700::1111/128", allowedIPEntry(net.ParseIP("2606:4700:4700::1111")))
+}
+
+func TestIsNoRecordError(t *testing.T) {
+	assert.True(t, isNoRecordError(&net.DNSError{Err: "no such host", IsNotFound: true}))
+	assert.False(t, isNoRecordError(&net.DNSError{Err: "server misbehaving", IsNotFound: false}))
+	assert.False(t, isNoRecordError(errors.New("some other error")))
+}
+
 func TestUser(t *testing.T) {
 	user := User{
 		Name: "name",
@@ -151,6 +457,13 @@ func TestUser(t *testing.T) {
 		}))
 	})
 
+	t.Run("combined interfaces", func(t *testing.T) {
+		assert.Equal(t, "name_0+1.conf", user.Filename(ClientConfig{
+			IF:         0,
+			CombineIFs: []int{1},
+		}))
+	})
+
 	t.Run("no-dns_route-dns", func(t *testing.T) {
 		assert.Equal(t, "name_0n.conf", user.Filename(ClientConfig{
 			RouteDNS: true,